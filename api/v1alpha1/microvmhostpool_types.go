@@ -0,0 +1,143 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// HostReadyCondition reports whether a MicrovmHostPoolReconciler could
+// successfully reach and probe a given host in the pool.
+const HostReadyCondition clusterv1.ConditionType = "HostReady"
+
+// HostCapacity describes how much of a flintlock host's resources are
+// available for new microvms.
+type HostCapacity struct {
+	// VCPU is the number of virtual CPUs the host can offer to microvms.
+	VCPU int64 `json:"vcpu"`
+	// MemoryMiB is the amount of memory, in MiB, the host can offer.
+	MemoryMiB int64 `json:"memoryMiB"`
+	// DiskGB is the amount of disk space, in GB, the host can offer.
+	DiskGB int64 `json:"diskGB"`
+}
+
+// Host describes a single flintlock host that's a member of a
+// MicrovmHostPool.
+type Host struct {
+	// Address is the host's flintlock gRPC endpoint, e.g. "10.0.0.5:9090".
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+
+	// TLSSecretRef names a Secret holding TLS client material for dialing
+	// Address. Neither MicrovmHostPoolReconciler nor MicrovmMachineReconciler
+	// reads this field: both dial Address through MvmClientFunc, which takes
+	// the address alone.
+	// +optional
+	TLSSecretRef *corev1.LocalObjectReference `json:"tlsSecretRef,omitempty"`
+
+	// Labels are attached to this host and can be used by a future selector
+	// to restrict which machines may land on it.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Capacity describes the host's total resources.
+	// +kubebuilder:validation:Required
+	Capacity HostCapacity `json:"capacity"`
+
+	// FailureDomain is the failure domain this host belongs to. While the
+	// host is ready, it is copied into Status.FailureDomains of every
+	// MicrovmCluster whose PlacementPolicy references this pool.
+	// +optional
+	FailureDomain string `json:"failureDomain,omitempty"`
+}
+
+// MicrovmHostPoolSpec defines the desired state of MicrovmHostPool.
+type MicrovmHostPoolSpec struct {
+	// Hosts is the set of flintlock hosts that make up this pool.
+	// +kubebuilder:validation:MinItems=1
+	Hosts []Host `json:"hosts"`
+}
+
+// HostStatus is the last observed state of a single host in the pool.
+type HostStatus struct {
+	// Address identifies which Spec.Hosts entry this status is for.
+	Address string `json:"address"`
+
+	// Ready is true when the most recent probe of this host succeeded.
+	Ready bool `json:"ready"`
+
+	// Allocated is the sum of capacity currently claimed by microvms placed
+	// on this host by this pool.
+	// +optional
+	Allocated HostCapacity `json:"allocated,omitempty"`
+
+	// FailureReason holds a short description of the last probe failure, if
+	// Ready is false.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// LastProbeTime is when this host was last probed.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// MicrovmHostPoolStatus defines the observed state of MicrovmHostPool.
+type MicrovmHostPoolStatus struct {
+	// Hosts reports the last observed state of every host in Spec.Hosts, in
+	// the same order.
+	// +optional
+	Hosts []HostStatus `json:"hosts,omitempty"`
+
+	// Ready is true when the most recent probe of every host in the pool
+	// succeeded.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// Conditions defines current service state of the MicrovmHostPool.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=mhp
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready"
+
+// MicrovmHostPool is the Schema for the microvmhostpools API. It is
+// cluster-scoped because a pool of flintlock hosts is shared infrastructure,
+// not owned by any single tenant cluster.
+type MicrovmHostPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MicrovmHostPoolSpec   `json:"spec,omitempty"`
+	Status MicrovmHostPoolStatus `json:"status,omitempty"`
+}
+
+// GetConditions returns the set of conditions for this object.
+func (p *MicrovmHostPool) GetConditions() clusterv1.Conditions {
+	return p.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (p *MicrovmHostPool) SetConditions(conditions clusterv1.Conditions) {
+	p.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// MicrovmHostPoolList contains a list of MicrovmHostPool.
+type MicrovmHostPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MicrovmHostPool `json:"items"`
+}
+
+//nolint:gochecknoinits // Standard kubebuilder scaffold pattern.
+func init() {
+	SchemeBuilder.Register(&MicrovmHostPool{}, &MicrovmHostPoolList{})
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the MicrovmHostPool validating webhook
+// with mgr.
+func (p *MicrovmHostPool) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(p).
+		Complete(); err != nil {
+		return fmt.Errorf("setting up MicrovmHostPool webhook: %w", err)
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1alpha1-microvmhostpool,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=microvmhostpools,verbs=create;update,versions=v1alpha1,name=validation.microvmhostpool.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &MicrovmHostPool{}
+
+// ValidateCreate implements webhook.CustomValidator.
+func (p *MicrovmHostPool) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pool, ok := obj.(*MicrovmHostPool)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmHostPool but got %T", obj))
+	}
+
+	return nil, pool.validate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator.
+func (p *MicrovmHostPool) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	pool, ok := newObj.(*MicrovmHostPool)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmHostPool but got %T", newObj))
+	}
+
+	return nil, pool.validate()
+}
+
+// ValidateDelete implements webhook.CustomValidator.
+func (p *MicrovmHostPool) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (p *MicrovmHostPool) validate() error {
+	var allErrs field.ErrorList
+
+	seen := map[string]bool{}
+
+	for i, host := range p.Spec.Hosts {
+		if host.Address == "" {
+			allErrs = append(allErrs, field.Required(
+				field.NewPath("spec", "hosts").Index(i).Child("address"),
+				"address is required",
+			))
+
+			continue
+		}
+
+		if seen[host.Address] {
+			allErrs = append(allErrs, field.Duplicate(
+				field.NewPath("spec", "hosts").Index(i).Child("address"),
+				host.Address,
+			))
+		}
+
+		seen[host.Address] = true
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "MicrovmHostPool"},
+		p.Name,
+		allErrs,
+	)
+}
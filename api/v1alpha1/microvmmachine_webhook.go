@@ -0,0 +1,98 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// machineWebhookClient is used to look up a MicrovmMachine's referenced
+// MicrovmHostPool during admission. It's set once by
+// MicrovmMachine.SetupWebhookWithManager; admission requests construct a
+// fresh MicrovmMachine from the request body, so there's nowhere on the
+// object itself to carry a client.
+var machineWebhookClient client.Client //nolint:gochecknoglobals // set once during webhook setup, read-only after.
+
+// SetupWebhookWithManager registers the MicrovmMachine validating webhook
+// with mgr.
+func (m *MicrovmMachine) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	machineWebhookClient = mgr.GetClient()
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(m).
+		Complete(); err != nil {
+		return fmt.Errorf("setting up MicrovmMachine webhook: %w", err)
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1alpha1-microvmmachine,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines,verbs=create;update,versions=v1alpha1,name=validation.microvmmachine.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &MicrovmMachine{}
+
+// ValidateCreate implements webhook.CustomValidator.
+func (m *MicrovmMachine) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	machine, ok := obj.(*MicrovmMachine)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmMachine but got %T", obj))
+	}
+
+	return nil, machine.validatePoolRef(ctx)
+}
+
+// ValidateUpdate implements webhook.CustomValidator.
+func (m *MicrovmMachine) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	machine, ok := newObj.(*MicrovmMachine)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmMachine but got %T", newObj))
+	}
+
+	return nil, machine.validatePoolRef(ctx)
+}
+
+// ValidateDelete implements webhook.CustomValidator.
+func (m *MicrovmMachine) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validatePoolRef rejects a MicrovmMachine whose Spec.PlacementPolicy
+// references a MicrovmHostPool that doesn't exist, so a typo in PoolRef is
+// caught at admission instead of surfacing as a reconcile error later.
+func (m *MicrovmMachine) validatePoolRef(ctx context.Context) error {
+	if m.Spec.PlacementPolicy == nil || m.Spec.PlacementPolicy.PoolRef == "" {
+		return nil
+	}
+
+	pool := &MicrovmHostPool{}
+
+	err := machineWebhookClient.Get(ctx, client.ObjectKey{Name: m.Spec.PlacementPolicy.PoolRef}, pool)
+	if err == nil {
+		return nil
+	}
+
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("looking up microvmhostpool %s: %w", m.Spec.PlacementPolicy.PoolRef, err)
+	}
+
+	allErrs := field.ErrorList{
+		field.NotFound(field.NewPath("spec", "placementPolicy", "poolRef"), m.Spec.PlacementPolicy.PoolRef),
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "MicrovmMachine"},
+		m.Name,
+		allErrs,
+	)
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package v1alpha1
+
+// PlacementStrategyType is the algorithm the MicrovmMachineReconciler uses to
+// pick a host for a new microvm out of a pool.
+type PlacementStrategyType string
+
+const (
+	// PlacementStrategySpread picks the ready host with the largest
+	// Capacity.VCPU. Allocation recorded in Status.Hosts is not taken into
+	// account.
+	PlacementStrategySpread PlacementStrategyType = "Spread"
+	// PlacementStrategyBinPack picks the first ready host in Spec.Hosts
+	// order; host utilisation is not taken into account.
+	PlacementStrategyBinPack PlacementStrategyType = "BinPack"
+	// PlacementStrategyRandom picks the first ready host in Spec.Hosts
+	// order; no random choice is made.
+	PlacementStrategyRandom PlacementStrategyType = "Random"
+)
+
+// PlacementPolicy selects which MicrovmHostPool a MicrovmCluster or
+// MicrovmMachine places its microvms into, and how hosts within that pool
+// are chosen.
+type PlacementPolicy struct {
+	// PoolRef is the name of the MicrovmHostPool (a cluster-scoped resource)
+	// to place microvms into.
+	// +kubebuilder:validation:Required
+	PoolRef string `json:"poolRef"`
+
+	// Strategy is the algorithm used to pick a host out of the pool.
+	// Defaults to Spread.
+	// +optional
+	// +kubebuilder:validation:Enum=Spread;BinPack;Random
+	// +kubebuilder:default=Spread
+	Strategy PlacementStrategyType `json:"strategy,omitempty"`
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Command flintsim boots the flintsim fake flintlock server and an envtest
+// API server together, points a real MicrovmMachineReconciler at both (using
+// the same controller-pkg/client.NewFlintlockClient factory main.go wires up
+// against a real flintlock host) and reconciles a single MicrovmMachine
+// end-to-end against them. It exists so that flow can be exercised by hand,
+// the same way `make kind-cluster` lets you do it against a real flintlock
+// host, then keeps both servers running until interrupted for further
+// poking.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	flintlockclient "github.com/liquidmetal-dev/controller-pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/clustercache"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/envtest"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/test/framework/flintsim"
+)
+
+// simMachineName is the name shared by the Cluster/Machine/MicrovmMachine
+// fixtures this command reconciles against the simulator.
+const simMachineName = "flintsim-machine"
+
+func main() {
+	if err := run(); err != nil {
+		klog.Background().Error(err, "flintsim exited with an error")
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	log := klog.Background()
+
+	sim, err := flintsim.New()
+	if err != nil {
+		return fmt.Errorf("creating flintsim server: %w", err)
+	}
+
+	sim.Start()
+	defer sim.Stop()
+
+	log.Info("flintsim listening", "address", sim.Addr())
+
+	env := envtest.NewTestEnvironment()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := env.Start(ctx); err != nil {
+		return fmt.Errorf("starting envtest environment: %w", err)
+	}
+	defer func() {
+		if err := env.Stop(); err != nil {
+			log.Error(err, "stopping envtest environment")
+		}
+	}()
+
+	log.Info("envtest API server ready")
+
+	mvmMachine, err := seedMachine(ctx, env, sim.Addr())
+	if err != nil {
+		return fmt.Errorf("seeding machine fixtures: %w", err)
+	}
+
+	reconciler := &controllers.MicrovmMachineReconciler{
+		Client:        env.GetClient(),
+		Scheme:        env.GetScheme(),
+		Recorder:      record.NewFakeRecorder(32),
+		MvmClientFunc: flintlockclient.NewFlintlockClient,
+		ClusterCache: clustercache.NewClusterCache(env.GetClient(), clustercache.Options{
+			Scheme: env.GetScheme(),
+		}),
+	}
+
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mvmMachine)})
+	if err != nil {
+		return fmt.Errorf("reconciling microvmmachine %s: %w", mvmMachine.Name, err)
+	}
+
+	log.Info("reconciled microvmmachine against flintsim", "requeueAfter", result.RequeueAfter)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+
+	return nil
+}
+
+// seedMachine creates the Cluster, Machine and MicrovmMachine objects needed
+// to drive a single MicrovmMachineReconciler.Reconcile call against the
+// flintsim server listening at hostAddr.
+func seedMachine(ctx context.Context, env *envtest.Environment, hostAddr string) (*infrav1.MicrovmMachine, error) {
+	ns, err := env.CreateNamespace(ctx, "flintsim")
+	if err != nil {
+		return nil, fmt.Errorf("creating namespace: %w", err)
+	}
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: simMachineName, Namespace: ns.Name},
+	}
+	if err := env.GetClient().Create(ctx, cluster); err != nil {
+		return nil, fmt.Errorf("creating cluster: %w", err)
+	}
+
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      simMachineName,
+			Namespace: ns.Name,
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+		},
+		Spec: clusterv1.MachineSpec{ClusterName: cluster.Name},
+	}
+	if err := env.GetClient().Create(ctx, machine); err != nil {
+		return nil, fmt.Errorf("creating machine: %w", err)
+	}
+
+	mvmMachine := &infrav1.MicrovmMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      simMachineName,
+			Namespace: ns.Name,
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Machine",
+					Name:       machine.Name,
+					UID:        machine.UID,
+				},
+			},
+		},
+		Spec: infrav1.MicrovmMachineSpec{Host: hostAddr},
+	}
+	if err := env.GetClient().Create(ctx, mvmMachine); err != nil {
+		return nil, fmt.Errorf("creating microvmmachine: %w", err)
+	}
+
+	return mvmMachine, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package clustercache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// healthzPath is probed on the tenant API server to decide connection health.
+const healthzPath = "/healthz"
+
+// accessor lazily builds a controller-runtime cache+client for one tenant
+// cluster and keeps it healthy with a periodic connection probe.
+type accessor struct {
+	key        client.ObjectKey
+	restConfig *rest.Config
+	scheme     *runtime.Scheme
+	interval   time.Duration
+
+	httpClient *http.Client
+	healthzURL string
+
+	mu        sync.Mutex
+	client    client.Client
+	cache     cache.Cache
+	status    HealthStatus
+	subs      []chan event.GenericEvent
+	cancel    context.CancelFunc
+	startOnce sync.Once
+}
+
+func newAccessor(key client.ObjectKey, restConfig *rest.Config, scheme *runtime.Scheme, interval time.Duration) (*accessor, error) {
+	// rest.HTTPClientFor, unlike rest.UnversionedRESTClientFor, doesn't need
+	// a NegotiatedSerializer - the health probe only ever reads a plain
+	// "ok" body off /healthz, never a typed Kubernetes object.
+	httpClient, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("building health-check client: %w", err)
+	}
+
+	return &accessor{
+		key:        key,
+		restConfig: restConfig,
+		scheme:     scheme,
+		interval:   interval,
+		httpClient: httpClient,
+		healthzURL: restConfig.Host + healthzPath,
+		status:     HealthUnknown,
+	}, nil
+}
+
+// start builds the accessor's cache+client in the background and kicks off
+// the periodic health probe. It is safe to call multiple times; only the
+// first call has an effect.
+func (a *accessor) start(ctx context.Context) {
+	a.startOnce.Do(func() {
+		runCtx, cancel := context.WithCancel(ctx)
+		a.cancel = cancel
+
+		go a.run(runCtx)
+	})
+}
+
+func (a *accessor) run(ctx context.Context) {
+	c, err := cache.New(a.restConfig, cache.Options{Scheme: a.scheme})
+	if err == nil {
+		go func() {
+			_ = c.Start(ctx)
+		}()
+
+		c.WaitForCacheSync(ctx)
+
+		cl, clientErr := client.New(a.restConfig, client.Options{Scheme: a.scheme, Cache: &client.CacheOptions{Reader: c}})
+		if clientErr == nil {
+			a.mu.Lock()
+			a.cache = c
+			a.client = cl
+			a.mu.Unlock()
+		}
+	}
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	a.probe(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.closeSubs()
+
+			return
+		case <-ticker.C:
+			a.probe(ctx)
+		}
+	}
+}
+
+// probe checks the tenant API server's /healthz endpoint and, on a change in
+// reachability, notifies every subscriber.
+func (a *accessor) probe(ctx context.Context) {
+	newStatus := HealthHealthy
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.healthzURL, nil)
+	if err != nil {
+		newStatus = HealthUnhealthy
+	} else if resp, err := a.httpClient.Do(req); err != nil {
+		newStatus = HealthUnhealthy
+	} else {
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			newStatus = HealthUnhealthy
+		}
+	}
+
+	a.mu.Lock()
+	changed := a.status != newStatus
+	a.status = newStatus
+	subs := append([]chan event.GenericEvent{}, a.subs...)
+	a.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: a.key.Name, Namespace: a.key.Namespace},
+	}
+
+	for _, ch := range subs {
+		select {
+		case ch <- event.GenericEvent{Object: cluster}:
+		default:
+			// Don't block the probe loop on a slow consumer; the next
+			// reconcile driven by sync period will still pick this up.
+		}
+	}
+}
+
+func (a *accessor) getClient() (client.Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client == nil {
+		return nil, fmt.Errorf("cluster %s: cache not ready yet", a.key)
+	}
+
+	return a.client, nil
+}
+
+func (a *accessor) health() HealthStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.status
+}
+
+func (a *accessor) subscribe() <-chan event.GenericEvent {
+	ch := make(chan event.GenericEvent, 1)
+
+	a.mu.Lock()
+	a.subs = append(a.subs, ch)
+	a.mu.Unlock()
+
+	return ch
+}
+
+func (a *accessor) stop() {
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+// closeSubs closes every subscriber channel, unblocking any goroutine
+// draining one with `for range`. It's only safe to call once run's probe
+// loop has actually stopped, so run does it itself on ctx.Done rather than
+// leaving it to stop, which only requests cancellation and returns
+// immediately.
+func (a *accessor) closeSubs() {
+	a.mu.Lock()
+	subs := a.subs
+	a.subs = nil
+	a.mu.Unlock()
+
+	for _, ch := range subs {
+		close(ch)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Package clustercache gives controllers cached, health-checked access to
+// tenant workload clusters, modeled on upstream cluster-api's ClusterCache.
+// Rather than each controller dialing the tenant API server ad hoc (and
+// paying for a fresh client/discovery round trip on every reconcile), a
+// single ClusterCache holds one long-lived accessor per Cluster and hands
+// out a ready-to-use client.Client once the tenant API server is reachable.
+package clustercache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// HealthStatus describes the last observed state of a tenant cluster's
+// connection, as produced by an accessor's periodic health probe.
+type HealthStatus int
+
+const (
+	// HealthUnknown means no probe has completed yet.
+	HealthUnknown HealthStatus = iota
+	// HealthHealthy means the most recent probe against /healthz succeeded.
+	HealthHealthy
+	// HealthUnhealthy means the most recent probe against /healthz failed.
+	HealthUnhealthy
+)
+
+// defaultHealthCheckInterval is how often an accessor probes its tenant
+// cluster's /healthz endpoint when the caller doesn't override it.
+const defaultHealthCheckInterval = 30 * time.Second
+
+// ClusterCache gives reconcilers a cached client.Client and rest.Config for
+// a tenant workload cluster, keyed by the owning Cluster's namespaced name.
+type ClusterCache interface {
+	// GetClient returns a cached client for the tenant cluster identified by
+	// key, constructing and starting an accessor for it on first use.
+	GetClient(ctx context.Context, key client.ObjectKey) (client.Client, error)
+	// GetRESTConfig returns the cached rest.Config for the tenant cluster.
+	GetRESTConfig(ctx context.Context, key client.ObjectKey) (*rest.Config, error)
+	// GetHealthStatus returns the last observed connection health for the
+	// tenant cluster, or HealthUnknown if no accessor exists yet.
+	GetHealthStatus(key client.ObjectKey) HealthStatus
+	// Subscribe returns a channel that receives a GenericEvent for key's
+	// owning Cluster every time the tenant cluster connects or disconnects.
+	// Callers wrap the channel in a controller-runtime source.Channel and
+	// Watch it so the owning Cluster gets re-enqueued on connectivity
+	// changes, rather than waiting out a fixed requeue interval.
+	Subscribe(ctx context.Context, key client.ObjectKey) (<-chan event.GenericEvent, error)
+	// Delete tears down and forgets the accessor for key, if one exists. It
+	// is called when the owning Cluster is deleted.
+	Delete(ctx context.Context, key client.ObjectKey) error
+}
+
+// KubeconfigGetter loads the tenant cluster's kubeconfig secret, returning
+// its raw bytes. This is satisfied by sigs.k8s.io/cluster-api/util/kubeconfig.
+type KubeconfigGetter func(ctx context.Context, c client.Client, key client.ObjectKey) ([]byte, error)
+
+// Options configures a Cache.
+type Options struct {
+	// Scheme is used to build each accessor's cache+client.
+	Scheme *runtime.Scheme
+	// HealthCheckInterval overrides how often accessors probe /healthz.
+	// Defaults to 30s.
+	HealthCheckInterval time.Duration
+	// GetKubeconfig loads a tenant cluster's kubeconfig. Defaults to
+	// DefaultGetKubeconfig.
+	GetKubeconfig KubeconfigGetter
+}
+
+// Cache is the default ClusterCache implementation: a registry of per-Cluster
+// accessors, built lazily and torn down on Delete.
+type Cache struct {
+	client        client.Client
+	scheme        *runtime.Scheme
+	probeInterval time.Duration
+	getKubeconfig KubeconfigGetter
+
+	mu        sync.Mutex
+	accessors map[client.ObjectKey]*accessor
+}
+
+var _ ClusterCache = &Cache{}
+
+// NewClusterCache returns a Cache that reads tenant kubeconfig secrets
+// through mgmtClient, the management cluster's own client.
+func NewClusterCache(mgmtClient client.Client, opts Options) *Cache {
+	interval := opts.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	getKubeconfig := opts.GetKubeconfig
+	if getKubeconfig == nil {
+		getKubeconfig = DefaultGetKubeconfig
+	}
+
+	return &Cache{
+		client:        mgmtClient,
+		scheme:        opts.Scheme,
+		probeInterval: interval,
+		getKubeconfig: getKubeconfig,
+		accessors:     map[client.ObjectKey]*accessor{},
+	}
+}
+
+// GetClient implements ClusterCache.
+func (c *Cache) GetClient(ctx context.Context, key client.ObjectKey) (client.Client, error) {
+	a, err := c.getOrCreateAccessor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.getClient()
+}
+
+// GetRESTConfig implements ClusterCache.
+func (c *Cache) GetRESTConfig(ctx context.Context, key client.ObjectKey) (*rest.Config, error) {
+	a, err := c.getOrCreateAccessor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.restConfig, nil
+}
+
+// GetHealthStatus implements ClusterCache.
+func (c *Cache) GetHealthStatus(key client.ObjectKey) HealthStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, ok := c.accessors[key]
+	if !ok {
+		return HealthUnknown
+	}
+
+	return a.health()
+}
+
+// Subscribe implements ClusterCache.
+func (c *Cache) Subscribe(ctx context.Context, key client.ObjectKey) (<-chan event.GenericEvent, error) {
+	a, err := c.getOrCreateAccessor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.subscribe(), nil
+}
+
+// Delete implements ClusterCache.
+func (c *Cache) Delete(ctx context.Context, key client.ObjectKey) error {
+	c.mu.Lock()
+	a, ok := c.accessors[key]
+	delete(c.accessors, key)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	a.stop()
+
+	return nil
+}
+
+func (c *Cache) getOrCreateAccessor(ctx context.Context, key client.ObjectKey) (*accessor, error) {
+	c.mu.Lock()
+	a, ok := c.accessors[key]
+	c.mu.Unlock()
+
+	if ok {
+		return a, nil
+	}
+
+	kubeconfig, err := c.getKubeconfig(ctx, c.client, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig for cluster %s: %w", key, err)
+	}
+
+	restConfig, err := restConfigFromKubeconfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("building rest config for cluster %s: %w", key, err)
+	}
+
+	a, err = newAccessor(key, restConfig, c.scheme, c.probeInterval)
+	if err != nil {
+		return nil, fmt.Errorf("creating accessor for cluster %s: %w", key, err)
+	}
+
+	c.mu.Lock()
+	c.accessors[key] = a
+	c.mu.Unlock()
+
+	a.start(ctx)
+
+	return a, nil
+}
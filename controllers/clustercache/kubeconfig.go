@@ -0,0 +1,35 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package clustercache
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultGetKubeconfig loads the <cluster-name>-kubeconfig Secret for key
+// using the cluster-api convention, and is the KubeconfigGetter NewClusterCache
+// uses when callers don't supply their own.
+func DefaultGetKubeconfig(ctx context.Context, c client.Client, key client.ObjectKey) ([]byte, error) {
+	data, err := kubeconfig.FromSecret(ctx, c, key)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeconfig secret for cluster %s: %w", key, err)
+	}
+
+	return data, nil
+}
+
+func restConfigFromKubeconfig(data []byte) (*rest.Config, error) {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig: %w", err)
+	}
+
+	return cfg, nil
+}
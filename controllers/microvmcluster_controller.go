@@ -0,0 +1,279 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/clustercache"
+)
+
+// MicrovmClusterFinalizer is added to a MicrovmCluster so this controller
+// gets a final reconcile with DeletionTimestamp set before the object is
+// removed. MicrovmCluster owns no external resources of its own (its
+// microvms are cleaned up by their own MicrovmMachine finalizers), so
+// reconcileDelete only ever has to clear finalizers, never wait on anything.
+const MicrovmClusterFinalizer = "microvmcluster.infrastructure.cluster.x-k8s.io"
+
+// apiServerNotReadyRequeueAfter is how soon a MicrovmCluster whose tenant
+// API server isn't reachable yet gets re-reconciled.
+const apiServerNotReadyRequeueAfter = 30 * time.Second
+
+// APIServerNotReadyReason is used on LoadBalancerAvailableCondition and
+// clusterv1.ReadyCondition while the tenant cluster's kubeconfig isn't
+// available yet, or while its API server is failing health checks.
+const APIServerNotReadyReason = "APIServerNotReady"
+
+// MicrovmClusterReconciler reconciles a MicrovmCluster object.
+type MicrovmClusterReconciler struct {
+	client.Client
+
+	Scheme           *runtime.Scheme
+	Recorder         record.EventRecorder
+	WatchFilterValue string
+
+	// ClusterCache gives cheap, health-checked access to each tenant
+	// cluster's kubeconfig and client, rather than dialling the tenant API
+	// server directly on every reconcile.
+	ClusterCache clustercache.ClusterCache
+
+	// clusterWatches tracks which tenant clusters this reconciler has
+	// already subscribed to via ClusterCache, so a cluster whose connection
+	// flaps doesn't grow a new subscription on every reconcile.
+	clusterWatchesMu sync.Mutex
+	clusterWatches   map[client.ObjectKey]bool
+
+	// clusterEvents is fed by ClusterCache.Subscribe and drained by the
+	// controller watch set up in SetupWithManager, so a tenant API server
+	// connecting or disconnecting re-enqueues its MicrovmCluster immediately
+	// instead of waiting out apiServerNotReadyRequeueAfter.
+	clusterEvents chan event.GenericEvent
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MicrovmClusterReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	r.clusterEvents = make(chan event.GenericEvent)
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.MicrovmCluster{}).
+		WatchesRawSource(source.Channel(r.clusterEvents, &handler.EnqueueRequestForObject{})).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating microvmcluster controller: %w", err)
+	}
+
+	return nil
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusters,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusters/status,verbs=get;update;patch
+
+// Reconcile brings a MicrovmCluster's status in line with its owning
+// Cluster's control-plane endpoint and the reachability of its tenant API
+// server.
+func (r *MicrovmClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
+	mvmCluster := &infrav1.MicrovmCluster{}
+	if err := r.Get(ctx, req.NamespacedName, mvmCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("getting microvmcluster %s: %w", req.NamespacedName, err)
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, mvmCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting owner cluster for microvmcluster %s: %w", req.NamespacedName, err)
+	}
+
+	if cluster == nil {
+		return ctrl.Result{}, nil
+	}
+
+	if annotations.IsPaused(cluster, mvmCluster) {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(mvmCluster, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("initialising patch helper for microvmcluster %s: %w", req.NamespacedName, err)
+	}
+
+	defer func() {
+		if patchErr := patchHelper.Patch(ctx, mvmCluster); patchErr != nil && reterr == nil {
+			reterr = fmt.Errorf("patching microvmcluster %s: %w", req.NamespacedName, patchErr)
+		}
+	}()
+
+	if !mvmCluster.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, cluster, mvmCluster)
+	}
+
+	controllerutil.AddFinalizer(mvmCluster, MicrovmClusterFinalizer)
+
+	return r.reconcileNormal(ctx, cluster, mvmCluster)
+}
+
+func (r *MicrovmClusterReconciler) reconcileNormal(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	mvmCluster *infrav1.MicrovmCluster,
+) (ctrl.Result, error) {
+	r.setFailureDomains(ctx, mvmCluster)
+
+	endpoint := mvmCluster.Spec.ControlPlaneEndpoint
+	if endpoint.Host == "" {
+		endpoint = cluster.Spec.ControlPlaneEndpoint
+	}
+
+	if endpoint.Host == "" {
+		return ctrl.Result{}, fmt.Errorf(
+			"waiting for a control plane endpoint on cluster %s or microvmcluster %s", cluster.Name, mvmCluster.Name)
+	}
+
+	key := client.ObjectKeyFromObject(cluster)
+
+	if _, err := r.ClusterCache.GetRESTConfig(ctx, key); err != nil {
+		conditions.MarkFalse(mvmCluster, infrav1.LoadBalancerAvailableCondition, APIServerNotReadyReason,
+			clusterv1.ConditionSeverityWarning, "waiting for tenant cluster kubeconfig to become available: %s", err)
+		conditions.MarkFalse(mvmCluster, clusterv1.ReadyCondition, APIServerNotReadyReason,
+			clusterv1.ConditionSeverityWarning, "")
+
+		return ctrl.Result{RequeueAfter: apiServerNotReadyRequeueAfter}, nil
+	}
+
+	r.ensureClusterWatch(ctx, key)
+
+	if r.ClusterCache.GetHealthStatus(key) == clustercache.HealthUnhealthy {
+		conditions.MarkFalse(mvmCluster, infrav1.LoadBalancerAvailableCondition, APIServerNotReadyReason,
+			clusterv1.ConditionSeverityWarning, "tenant cluster %s has a kubeconfig but its API server is failing health checks", key)
+		conditions.MarkFalse(mvmCluster, clusterv1.ReadyCondition, APIServerNotReadyReason,
+			clusterv1.ConditionSeverityWarning, "")
+
+		return ctrl.Result{RequeueAfter: apiServerNotReadyRequeueAfter}, nil
+	}
+
+	mvmCluster.Status.Ready = true
+	conditions.MarkTrue(mvmCluster, infrav1.LoadBalancerAvailableCondition)
+	conditions.MarkTrue(mvmCluster, clusterv1.ReadyCondition)
+
+	return ctrl.Result{}, nil
+}
+
+// ensureClusterWatch subscribes to key's ClusterCache connectivity events the
+// first time it's reconciled, forwarding them into the watch set up in
+// SetupWithManager so a tenant API server connecting or disconnecting
+// re-enqueues its MicrovmCluster immediately. It's a no-op on every
+// subsequent call for the same key.
+func (r *MicrovmClusterReconciler) ensureClusterWatch(ctx context.Context, key client.ObjectKey) {
+	r.clusterWatchesMu.Lock()
+
+	if r.clusterWatches == nil {
+		r.clusterWatches = map[client.ObjectKey]bool{}
+	}
+
+	if r.clusterWatches[key] {
+		r.clusterWatchesMu.Unlock()
+
+		return
+	}
+
+	r.clusterWatches[key] = true
+	r.clusterWatchesMu.Unlock()
+
+	events, err := r.ClusterCache.Subscribe(ctx, key)
+	if err != nil {
+		r.clusterWatchesMu.Lock()
+		delete(r.clusterWatches, key)
+		r.clusterWatchesMu.Unlock()
+
+		return
+	}
+
+	go r.forwardClusterEvents(events)
+}
+
+// forwardClusterEvents drains a single ClusterCache subscription until its
+// channel is closed, which happens when the tenant cluster's accessor stops,
+// relaying events onto r.clusterEvents without blocking the probe loop that
+// produces them.
+func (r *MicrovmClusterReconciler) forwardClusterEvents(events <-chan event.GenericEvent) {
+	for ev := range events {
+		if r.clusterEvents == nil {
+			continue
+		}
+
+		select {
+		case r.clusterEvents <- ev:
+		default:
+		}
+	}
+}
+
+// setFailureDomains copies the failure domains reported by mvmCluster's
+// referenced MicrovmHostPool into Status.FailureDomains, independent of
+// whether the tenant API server is reachable yet.
+func (r *MicrovmClusterReconciler) setFailureDomains(ctx context.Context, mvmCluster *infrav1.MicrovmCluster) {
+	if mvmCluster.Spec.PlacementPolicy == nil || mvmCluster.Spec.PlacementPolicy.PoolRef == "" {
+		return
+	}
+
+	pool := &infrav1.MicrovmHostPool{}
+	if err := r.Get(ctx, client.ObjectKey{Name: mvmCluster.Spec.PlacementPolicy.PoolRef}, pool); err != nil {
+		return
+	}
+
+	domains := clusterv1.FailureDomains{}
+	for domain := range FailureDomains(pool) {
+		domains[domain] = clusterv1.FailureDomainSpec{ControlPlane: true}
+	}
+
+	mvmCluster.Status.FailureDomains = domains
+}
+
+// reconcileDelete tears down the ClusterCache accessor for cluster, so its
+// background cache and health probe stop rather than leaking for as long as
+// the manager runs, then clears mvmCluster's finalizers so the API server
+// can remove it. MicrovmCluster has no other direct cleanup of its own to do
+// here. Every finalizer on mvmCluster is cleared, including any not added by
+// this controller, rather than only MicrovmClusterFinalizer.
+func (r *MicrovmClusterReconciler) reconcileDelete(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	mvmCluster *infrav1.MicrovmCluster,
+) (ctrl.Result, error) {
+	key := client.ObjectKeyFromObject(cluster)
+
+	if err := r.ClusterCache.Delete(ctx, key); err != nil {
+		return ctrl.Result{}, fmt.Errorf("tearing down cluster cache for microvmcluster %s: %w", mvmCluster.Name, err)
+	}
+
+	r.clusterWatchesMu.Lock()
+	delete(r.clusterWatches, key)
+	r.clusterWatchesMu.Unlock()
+
+	mvmCluster.ObjectMeta.Finalizers = nil
+
+	return ctrl.Result{}, nil
+}
@@ -13,7 +13,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
@@ -21,22 +20,44 @@ import (
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
 )
 
+// createReadyHostPool creates a cluster-scoped MicrovmHostPool named name
+// with a single host already reporting ready in one failure domain, so a
+// MicrovmCluster referencing it via Spec.PlacementPolicy gets a non-empty
+// Status.FailureDomains without needing a real MicrovmHostPoolReconciler
+// pass in these tests.
+func createReadyHostPool(ctx context.Context, g *WithT, name string) {
+	pool := &infrav1.MicrovmHostPool{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: infrav1.MicrovmHostPoolSpec{
+			Hosts: []infrav1.Host{
+				{
+					Address:       "10.0.0.1:9090",
+					Capacity:      infrav1.HostCapacity{VCPU: 4, MemoryMiB: 4096},
+					FailureDomain: "fd-1",
+				},
+			},
+		},
+	}
+	g.Expect(testEnv.GetClient().Create(ctx, pool)).To(Succeed())
+
+	pool.Status.Hosts = []infrav1.HostStatus{{Address: "10.0.0.1:9090", Ready: true}}
+	g.Expect(testEnv.GetClient().Status().Update(ctx, pool)).To(Succeed())
+}
+
 func TestClusterReconciliationNoEndpoint(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	objects := []runtime.Object{
-		createCluster(),
-		createMicrovmCluster(),
-	}
+	createObjects(ctx, g, createCluster(ns.Name), createMicrovmCluster(ns.Name))
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).To(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	reconciled, err := getMicrovmCluster(ctx, ns.Name)
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(reconciled.Status.Ready).To(BeFalse())
 
@@ -46,174 +67,149 @@ func TestClusterReconciliationNoEndpoint(t *testing.T) {
 
 func TestClusterReconciliationWithClusterEndpoint(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	cluster := createCluster()
+	cluster := createCluster(ns.Name)
 	cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
 		Host: "192.168.8.15",
 		Port: 6443,
 	}
 
-	tenantClusterNodes := &corev1.NodeList{
-		Items: []corev1.Node{
-			{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "node1",
-				},
-			},
-		},
-	}
+	mvmCluster := createMicrovmCluster(ns.Name)
+	mvmCluster.Spec.PlacementPolicy = &infrav1.PlacementPolicy{PoolRef: "pool-" + ns.Name}
+	createReadyHostPool(ctx, g, mvmCluster.Spec.PlacementPolicy.PoolRef)
 
-	objects := []runtime.Object{
-		cluster,
-		createMicrovmCluster(),
-		tenantClusterNodes,
-	}
+	createObjects(ctx, g, cluster, mvmCluster)
+	g.Expect(testEnv.CreateKubeconfigSecret(ctx, cluster, "https://192.168.8.15:6443")).To(Succeed())
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	reconciled, err := getMicrovmCluster(ctx, ns.Name)
 	g.Expect(err).NotTo(HaveOccurred())
-	// TODO: renable these assertions when moved to envtest
-	// g.Expect(reconciled.Status.Ready).To(BeTrue())
-	// g.Expect(reconciled.Status.FailureDomains).To(HaveLen(1))
+	g.Expect(reconciled.Status.Ready).To(BeTrue())
+	g.Expect(reconciled.Status.FailureDomains).To(HaveLen(1))
 
-	// c := conditions.Get(reconciled, infrav1.LoadBalancerAvailableCondition)
-	// g.Expect(c).ToNot(BeNil())
-	// g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
+	c := conditions.Get(reconciled, infrav1.LoadBalancerAvailableCondition)
+	g.Expect(c).ToNot(BeNil())
+	g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
 
-	// c = conditions.Get(reconciled, clusterv1.ReadyCondition)
-	// g.Expect(c).ToNot(BeNil())
-	// g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
+	c = conditions.Get(reconciled, clusterv1.ReadyCondition)
+	g.Expect(c).ToNot(BeNil())
+	g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
 }
 
 func TestClusterReconciliationWithMvmClusterEndpoint(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	mvmCluster := createMicrovmCluster()
+	cluster := createCluster(ns.Name)
+	mvmCluster := createMicrovmCluster(ns.Name)
 	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
 		Host: "192.168.8.15",
 		Port: 6443,
 	}
+	mvmCluster.Spec.PlacementPolicy = &infrav1.PlacementPolicy{PoolRef: "pool-" + ns.Name}
+	createReadyHostPool(ctx, g, mvmCluster.Spec.PlacementPolicy.PoolRef)
 
-	tenantClusterNodes := &corev1.NodeList{
-		Items: []corev1.Node{
-			{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "node1",
-				},
-			},
-		},
-	}
-
-	objects := []runtime.Object{
-		createCluster(),
-		mvmCluster,
-		tenantClusterNodes,
-	}
+	createObjects(ctx, g, cluster, mvmCluster)
+	g.Expect(testEnv.CreateKubeconfigSecret(ctx, cluster, "https://192.168.8.15:6443")).To(Succeed())
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	reconciled, err := getMicrovmCluster(ctx, ns.Name)
 	g.Expect(err).NotTo(HaveOccurred())
-	// TODO: enable these assertions when moved to envtest
-	// g.Expect(reconciled.Status.Ready).To(BeTrue())
-	// g.Expect(reconciled.Status.FailureDomains).To(HaveLen(1))
+	g.Expect(reconciled.Status.Ready).To(BeTrue())
+	g.Expect(reconciled.Status.FailureDomains).To(HaveLen(1))
 
-	// c := conditions.Get(reconciled, infrav1.LoadBalancerAvailableCondition)
-	// g.Expect(c).ToNot(BeNil())
-	// g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
+	c := conditions.Get(reconciled, infrav1.LoadBalancerAvailableCondition)
+	g.Expect(c).ToNot(BeNil())
+	g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
 
-	// c = conditions.Get(reconciled, clusterv1.ReadyCondition)
-	// g.Expect(c).ToNot(BeNil())
-	// g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
+	c = conditions.Get(reconciled, clusterv1.ReadyCondition)
+	g.Expect(c).ToNot(BeNil())
+	g.Expect(c.Status).To(Equal(corev1.ConditionTrue))
 }
 
 func TestClusterReconciliationWithClusterEndpointAPIServerNotReady(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	cluster := createCluster()
+	cluster := createCluster(ns.Name)
 	cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
 		Host: "192.168.8.15",
 		Port: 6443,
 	}
 
-	tenantClusterNodes := &corev1.NodeList{
-		Items: []corev1.Node{},
-	}
+	mvmCluster := createMicrovmCluster(ns.Name)
+	mvmCluster.Spec.PlacementPolicy = &infrav1.PlacementPolicy{PoolRef: "pool-" + ns.Name}
+	createReadyHostPool(ctx, g, mvmCluster.Spec.PlacementPolicy.PoolRef)
 
-	objects := []runtime.Object{
-		cluster,
-		createMicrovmCluster(),
-		tenantClusterNodes,
-	}
+	createObjects(ctx, g, cluster, mvmCluster)
+	// No kubeconfig secret is created, so the tenant cluster is unreachable
+	// and the reconciler takes the "API server not ready" path.
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(30 * time.Second)))
 
-	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	reconciled, err := getMicrovmCluster(ctx, ns.Name)
 	g.Expect(err).NotTo(HaveOccurred())
-	// TODO: renable these assertions when moved to envtest
-	// g.Expect(reconciled.Status.Ready).To(BeTrue())
-	// g.Expect(reconciled.Status.FailureDomains).To(HaveLen(1))
+	g.Expect(reconciled.Status.FailureDomains).To(HaveLen(1))
 
-	// c := conditions.Get(reconciled, infrav1.LoadBalancerAvailableCondition)
-	// g.Expect(c).ToNot(BeNil())
-	// g.Expect(c.Status).To(Equal(corev1.ConditionFalse))
+	c := conditions.Get(reconciled, infrav1.LoadBalancerAvailableCondition)
+	g.Expect(c).ToNot(BeNil())
+	g.Expect(c.Status).To(Equal(corev1.ConditionFalse))
 
-	// c = conditions.Get(reconciled, clusterv1.ReadyCondition)
-	// g.Expect(c).ToNot(BeNil())
-	// g.Expect(c.Status).To(Equal(corev1.ConditionFalse))
+	c = conditions.Get(reconciled, clusterv1.ReadyCondition)
+	g.Expect(c).ToNot(BeNil())
+	g.Expect(c.Status).To(Equal(corev1.ConditionFalse))
 }
 
 func TestClusterReconciliationMicrovmAlreadyDeleted(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	objects := []runtime.Object{}
-
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	_, err = getMicrovmCluster(ctx, ns.Name)
 	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
 }
 
 func TestClusterReconciliationNotOwner(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	mvmCluster := createMicrovmCluster()
+	mvmCluster := createMicrovmCluster(ns.Name)
 	mvmCluster.ObjectMeta.OwnerReferences = nil
 
-	objects := []runtime.Object{
-		createCluster(),
-		mvmCluster,
-	}
+	createObjects(ctx, g, createCluster(ns.Name), mvmCluster)
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	reconciled, err := getMicrovmCluster(ctx, ns.Name)
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(reconciled.Status.Ready).To(BeFalse())
 
@@ -223,25 +219,23 @@ func TestClusterReconciliationNotOwner(t *testing.T) {
 
 func TestClusterReconciliationWhenPaused(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	mvmCluster := createMicrovmCluster()
+	mvmCluster := createMicrovmCluster(ns.Name)
 	mvmCluster.ObjectMeta.Annotations = map[string]string{
 		clusterv1.PausedAnnotation: "true",
 	}
 
-	objects := []runtime.Object{
-		createCluster(),
-		mvmCluster,
-	}
+	createObjects(ctx, g, createCluster(ns.Name), mvmCluster)
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	reconciled, err := getMicrovmCluster(ctx, ns.Name)
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(reconciled.Status.Ready).To(BeFalse())
 
@@ -251,28 +245,26 @@ func TestClusterReconciliationWhenPaused(t *testing.T) {
 
 func TestClusterReconciliationDelete(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
 
-	mvmCluster := createMicrovmCluster()
-	mvmCluster.ObjectMeta.DeletionTimestamp = &metav1.Time{
-		Time: time.Now(),
-	}
+	mvmCluster := createMicrovmCluster(ns.Name)
 	mvmCluster.Finalizers = []string{
 		"somefinalizer",
 	}
 
-	objects := []runtime.Object{
-		createCluster(),
-		mvmCluster,
-	}
+	createObjects(ctx, g, createCluster(ns.Name), mvmCluster)
+	g.Expect(testEnv.GetClient().Delete(ctx, mvmCluster)).To(Succeed())
 
-	client := createFakeClient(g, objects)
-	result, err := reconcileCluster(client)
+	result, err := reconcileCluster(ns.Name)
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
 	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-	// TODO: when we move to envtest this should return an NotFound error. #30
-	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
-	g.Expect(err).NotTo(HaveOccurred())
+	// The real API server removes the object once the last finalizer is
+	// cleared by the reconciler, unlike the old fake client which left it
+	// behind for inspection.
+	_, err = getMicrovmCluster(ctx, ns.Name)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
 }
@@ -0,0 +1,179 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+)
+
+// hostPoolProbeRequeue is how soon a MicrovmHostPool gets re-reconciled to
+// refresh its host probes, mirroring the cadence clustercache uses for
+// tenant-cluster health checks.
+const hostPoolProbeRequeue = 30 * time.Second
+
+// hostProbeTimeout bounds how long a single host probe may take, so one
+// unreachable host can't stall the whole pool's reconcile.
+const hostProbeTimeout = 5 * time.Second
+
+// MicrovmHostPoolReconciler reconciles a MicrovmHostPool object, probing
+// every configured flintlock host and reporting readiness into the pool's
+// status. Failure domains are not stored in the status; FailureDomains
+// derives them from the ready hosts.
+type MicrovmHostPoolReconciler struct {
+	client.Client
+
+	Scheme           *runtime.Scheme
+	Recorder         record.EventRecorder
+	WatchFilterValue string
+
+	// MvmClientFunc builds a client for a host's flintlock endpoint, the same
+	// factory MicrovmMachineReconciler uses. ProbeHost defaults to using it
+	// for a real flintlock health check; tests leave it nil and override
+	// ProbeHost directly instead.
+	MvmClientFunc MvmClientFunc
+
+	// ProbeHost dials address and reports whether the host is reachable. It
+	// defaults to defaultProbeHost; tests override it to avoid needing a
+	// real (or simulated) flintlock host.
+	ProbeHost func(ctx context.Context, address string) error
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MicrovmHostPoolReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if r.ProbeHost == nil {
+		r.ProbeHost = r.defaultProbeHost
+	}
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.MicrovmHostPool{}).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating microvmhostpool controller: %w", err)
+	}
+
+	return nil
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmhostpools,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmhostpools/status,verbs=get;update;patch
+
+// Reconcile probes every host in a MicrovmHostPool and records their
+// readiness into Status.Hosts.
+func (r *MicrovmHostPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pool := &infrav1.MicrovmHostPool{}
+	if err := r.Get(ctx, req.NamespacedName, pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("getting microvmhostpool %s: %w", req.Name, err)
+	}
+
+	if r.ProbeHost == nil {
+		r.ProbeHost = r.defaultProbeHost
+	}
+
+	statuses := make([]infrav1.HostStatus, 0, len(pool.Spec.Hosts))
+	allReady := true
+
+	for _, host := range pool.Spec.Hosts {
+		hostStatus := infrav1.HostStatus{
+			Address:       host.Address,
+			LastProbeTime: metav1.Now(),
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, hostProbeTimeout)
+		err := r.ProbeHost(probeCtx, host.Address)
+		cancel()
+
+		if err != nil {
+			hostStatus.Ready = false
+			hostStatus.FailureReason = err.Error()
+			allReady = false
+		} else {
+			hostStatus.Ready = true
+		}
+
+		statuses = append(statuses, hostStatus)
+	}
+
+	pool.Status.Hosts = statuses
+	pool.Status.Ready = allReady
+
+	if err := r.Status().Update(ctx, pool); err != nil {
+		return ctrl.Result{}, fmt.Errorf("updating microvmhostpool %s status: %w", req.Name, err)
+	}
+
+	return ctrl.Result{RequeueAfter: hostPoolProbeRequeue}, nil
+}
+
+// defaultProbeHost probes address by using MvmClientFunc to list its
+// microvms, the same flintlock client factory MicrovmMachineReconciler uses
+// to talk to a host. If no MvmClientFunc is configured, it falls back to a
+// plain TCP dial against the flintlock gRPC port.
+func (r *MicrovmHostPoolReconciler) defaultProbeHost(ctx context.Context, address string) error {
+	if r.MvmClientFunc == nil {
+		return dialProbe(ctx, address)
+	}
+
+	mvmClient, err := r.MvmClientFunc(address)
+	if err != nil {
+		return fmt.Errorf("dialing host %s: %w", address, err)
+	}
+
+	if _, err := mvmClient.ListMicroVMs(ctx); err != nil {
+		return fmt.Errorf("probing host %s: %w", address, err)
+	}
+
+	return nil
+}
+
+// dialProbe is the fallback ProbeHost used when no MvmClientFunc is
+// configured: a plain TCP dial against the flintlock gRPC port, good enough
+// to detect a host that's down or unreachable without depending on
+// flintlock-specific RPCs.
+func dialProbe(ctx context.Context, address string) error {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("dialing host %s: %w", address, err)
+	}
+
+	return conn.Close()
+}
+
+// FailureDomains returns the set of failure domains reported by ready hosts
+// in pool, for a MicrovmCluster to copy into its own Status.FailureDomains.
+func FailureDomains(pool *infrav1.MicrovmHostPool) map[string]bool {
+	readyByAddress := make(map[string]bool, len(pool.Status.Hosts))
+	for _, hs := range pool.Status.Hosts {
+		readyByAddress[hs.Address] = hs.Ready
+	}
+
+	domains := map[string]bool{}
+
+	for _, host := range pool.Spec.Hosts {
+		if host.FailureDomain == "" || !readyByAddress[host.Address] {
+			continue
+		}
+
+		domains[host.FailureDomain] = true
+	}
+
+	return domains
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+)
+
+func createHostPool(name string, hosts ...infrav1.Host) *infrav1.MicrovmHostPool {
+	return &infrav1.MicrovmHostPool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: infrav1.MicrovmHostPoolSpec{
+			Hosts: hosts,
+		},
+	}
+}
+
+func TestHostPoolReconciliationAllHostsReady(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	pool := createHostPool("pool-ready",
+		infrav1.Host{Address: "10.0.0.1:9090", Capacity: infrav1.HostCapacity{VCPU: 4, MemoryMiB: 4096}, FailureDomain: "fd-1"},
+		infrav1.Host{Address: "10.0.0.2:9090", Capacity: infrav1.HostCapacity{VCPU: 4, MemoryMiB: 4096}, FailureDomain: "fd-2"},
+	)
+	createObjects(ctx, g, pool)
+
+	reconciler := &controllers.MicrovmHostPoolReconciler{
+		Client:    testEnv.GetClient(),
+		ProbeHost: func(context.Context, string) error { return nil },
+	}
+
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: pool.Name}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).NotTo(BeZero())
+
+	reconciled := &infrav1.MicrovmHostPool{}
+	g.Expect(testEnv.GetClient().Get(ctx, types.NamespacedName{Name: pool.Name}, reconciled)).To(Succeed())
+	g.Expect(reconciled.Status.Ready).To(BeTrue())
+	g.Expect(reconciled.Status.Hosts).To(HaveLen(2))
+
+	domains := controllers.FailureDomains(reconciled)
+	g.Expect(domains).To(HaveLen(2))
+	g.Expect(domains).To(HaveKey("fd-1"))
+	g.Expect(domains).To(HaveKey("fd-2"))
+}
+
+func TestHostPoolReconciliationUnreachableHost(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	pool := createHostPool("pool-unreachable",
+		infrav1.Host{Address: "10.0.0.9:9090", Capacity: infrav1.HostCapacity{VCPU: 4, MemoryMiB: 4096}, FailureDomain: "fd-1"},
+	)
+	createObjects(ctx, g, pool)
+
+	reconciler := &controllers.MicrovmHostPoolReconciler{
+		Client:    testEnv.GetClient(),
+		ProbeHost: func(context.Context, string) error { return errors.New("connection refused") },
+	}
+
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: pool.Name}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled := &infrav1.MicrovmHostPool{}
+	g.Expect(testEnv.GetClient().Get(ctx, types.NamespacedName{Name: pool.Name}, reconciled)).To(Succeed())
+	g.Expect(reconciled.Status.Ready).To(BeFalse())
+	g.Expect(reconciled.Status.Hosts[0].Ready).To(BeFalse())
+	g.Expect(reconciled.Status.Hosts[0].FailureReason).NotTo(BeEmpty())
+
+	g.Expect(controllers.FailureDomains(reconciled)).To(BeEmpty())
+}
@@ -0,0 +1,280 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/clustercache"
+)
+
+// MicrovmMachineFinalizer is added to a MicrovmMachine so this controller
+// gets a final reconcile with DeletionTimestamp set before the object is
+// removed, giving it a chance to delete the backing microvm from its host.
+const MicrovmMachineFinalizer = "microvmmachine.infrastructure.cluster.x-k8s.io"
+
+// MvmClient is the subset of a flintlock client this controller needs. It's
+// satisfied by controller-pkg/client.NewFlintlockClient's return value.
+//
+// DeleteMicroVM must treat a microvm that's already gone as success, so
+// reconcileDelete can call it unconditionally without first checking
+// whether a microvm was ever created.
+type MvmClient interface {
+	ListMicroVMs(ctx context.Context) ([]string, error)
+	CreateMicroVM(ctx context.Context, id string) error
+	GetMicroVM(ctx context.Context, id string) (bool, error)
+	DeleteMicroVM(ctx context.Context, id string) error
+}
+
+// MvmClientFunc dials a flintlock host and returns a client for it, matching
+// controller-pkg/client.NewFlintlockClient's signature.
+type MvmClientFunc func(address string) (MvmClient, error)
+
+// MicrovmMachineReconciler reconciles a MicrovmMachine object.
+type MicrovmMachineReconciler struct {
+	client.Client
+
+	Scheme           *runtime.Scheme
+	Recorder         record.EventRecorder
+	WatchFilterValue string
+
+	// MvmClientFunc builds a client for a machine's flintlock host.
+	MvmClientFunc MvmClientFunc
+
+	// ClusterCache gives access to the tenant cluster's client, used to
+	// confirm a machine's backing Node has joined before marking it ready.
+	ClusterCache clustercache.ClusterCache
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MicrovmMachineReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.MicrovmMachine{}).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating microvmmachine controller: %w", err)
+	}
+
+	return nil
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines/status,verbs=get;update;patch
+
+// Reconcile picks a flintlock host for a MicrovmMachine (from the
+// MicrovmHostPool its own Spec.PlacementPolicy references, if one is set),
+// makes sure a microvm
+// backing it exists on that host, and confirms the resulting Node has
+// joined the tenant cluster.
+func (r *MicrovmMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
+	mvmMachine := &infrav1.MicrovmMachine{}
+	if err := r.Get(ctx, req.NamespacedName, mvmMachine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("getting microvmmachine %s: %w", req.NamespacedName, err)
+	}
+
+	machine, err := util.GetOwnerMachine(ctx, r.Client, mvmMachine.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting owner machine for microvmmachine %s: %w", req.NamespacedName, err)
+	}
+
+	if machine == nil {
+		return ctrl.Result{}, nil
+	}
+
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machine.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting cluster for machine %s: %w", machine.Name, err)
+	}
+
+	if annotations.IsPaused(cluster, mvmMachine) {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(mvmMachine, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("initialising patch helper for microvmmachine %s: %w", req.NamespacedName, err)
+	}
+
+	defer func() {
+		if patchErr := patchHelper.Patch(ctx, mvmMachine); patchErr != nil && reterr == nil {
+			reterr = fmt.Errorf("patching microvmmachine %s: %w", req.NamespacedName, patchErr)
+		}
+	}()
+
+	if !mvmMachine.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, mvmMachine)
+	}
+
+	controllerutil.AddFinalizer(mvmMachine, MicrovmMachineFinalizer)
+
+	return r.reconcileNormal(ctx, cluster, mvmMachine)
+}
+
+func (r *MicrovmMachineReconciler) reconcileNormal(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	mvmMachine *infrav1.MicrovmMachine,
+) (ctrl.Result, error) {
+	if err := r.assignHost(ctx, mvmMachine); err != nil {
+		return ctrl.Result{}, fmt.Errorf("assigning host to microvmmachine %s: %w", mvmMachine.Name, err)
+	}
+
+	if err := r.reconcileMicroVM(ctx, mvmMachine); err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconciling microvm for microvmmachine %s: %w", mvmMachine.Name, err)
+	}
+
+	tenantClient, err := r.ClusterCache.GetClient(ctx, client.ObjectKeyFromObject(cluster))
+	if err != nil {
+		return ctrl.Result{RequeueAfter: apiServerNotReadyRequeueAfter}, nil //nolint:nilerr // requeue and try again once the tenant cluster is reachable.
+	}
+
+	// There's no Spec.ProviderID on MicrovmMachine to match against a Node's
+	// Spec.ProviderID, so this correlates on the tenant Node's name instead,
+	// which the kubelet's --hostname-override sets to the machine's name in
+	// every bootstrap flow this provider ships.
+	var node corev1.Node
+	if err := tenantClient.Get(ctx, client.ObjectKey{Name: mvmMachine.Name}, &node); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("getting tenant cluster node for microvmmachine %s: %w", mvmMachine.Name, err)
+		}
+
+		mvmMachine.Status.Ready = false
+	} else {
+		mvmMachine.Status.Ready = true
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileMicroVM makes sure a microvm backing mvmMachine exists on its
+// assigned host, dialing the host through MvmClientFunc the same way
+// MicrovmHostPoolReconciler.defaultProbeHost does. It's a no-op until a host
+// has been assigned.
+func (r *MicrovmMachineReconciler) reconcileMicroVM(ctx context.Context, mvmMachine *infrav1.MicrovmMachine) error {
+	if mvmMachine.Spec.Host == "" {
+		return nil
+	}
+
+	mvmClient, err := r.MvmClientFunc(mvmMachine.Spec.Host)
+	if err != nil {
+		return fmt.Errorf("dialing host %s: %w", mvmMachine.Spec.Host, err)
+	}
+
+	id := string(mvmMachine.UID)
+
+	exists, err := mvmClient.GetMicroVM(ctx, id)
+	if err != nil {
+		return fmt.Errorf("getting microvm %s on host %s: %w", id, mvmMachine.Spec.Host, err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	if err := mvmClient.CreateMicroVM(ctx, id); err != nil {
+		return fmt.Errorf("creating microvm %s on host %s: %w", id, mvmMachine.Spec.Host, err)
+	}
+
+	return nil
+}
+
+// assignHost picks a host address for mvmMachine out of the MicrovmHostPool
+// referenced by its own Spec.PlacementPolicy, if one isn't already assigned.
+// The MicrovmCluster's PlacementPolicy is not consulted. It replaces
+// requiring the user to hard-code a host address per machine.
+func (r *MicrovmMachineReconciler) assignHost(ctx context.Context, mvmMachine *infrav1.MicrovmMachine) error {
+	if mvmMachine.Spec.Host != "" {
+		return nil
+	}
+
+	if mvmMachine.Spec.PlacementPolicy == nil || mvmMachine.Spec.PlacementPolicy.PoolRef == "" {
+		return nil
+	}
+
+	pool := &infrav1.MicrovmHostPool{}
+	if err := r.Get(ctx, client.ObjectKey{Name: mvmMachine.Spec.PlacementPolicy.PoolRef}, pool); err != nil {
+		return fmt.Errorf("getting microvmhostpool %s: %w", mvmMachine.Spec.PlacementPolicy.PoolRef, err)
+	}
+
+	address, err := pickHost(pool, mvmMachine.Spec.PlacementPolicy.Strategy)
+	if err != nil {
+		return err
+	}
+
+	mvmMachine.Spec.Host = address
+
+	return nil
+}
+
+// pickHost selects a ready host address out of pool according to strategy.
+// PlacementStrategySpread picks the ready host with the largest
+// Capacity.VCPU; any other strategy, BinPack and Random included, picks the
+// first ready host.
+func pickHost(pool *infrav1.MicrovmHostPool, strategy infrav1.PlacementStrategyType) (string, error) {
+	readyByAddress := make(map[string]bool, len(pool.Status.Hosts))
+	for _, hs := range pool.Status.Hosts {
+		readyByAddress[hs.Address] = hs.Ready
+	}
+
+	var best *infrav1.Host
+
+	for i, host := range pool.Spec.Hosts {
+		if !readyByAddress[host.Address] {
+			continue
+		}
+
+		if strategy != infrav1.PlacementStrategySpread {
+			return host.Address, nil
+		}
+
+		if best == nil || host.Capacity.VCPU > best.Capacity.VCPU {
+			best = &pool.Spec.Hosts[i]
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("no ready host available in microvmhostpool %s", pool.Name)
+	}
+
+	return best.Address, nil
+}
+
+// reconcileDelete deletes mvmMachine's backing microvm from its host, if one
+// was ever assigned, before clearing the finalizer so the API server can
+// remove the object.
+func (r *MicrovmMachineReconciler) reconcileDelete(ctx context.Context, mvmMachine *infrav1.MicrovmMachine) (ctrl.Result, error) {
+	if mvmMachine.Spec.Host != "" {
+		mvmClient, err := r.MvmClientFunc(mvmMachine.Spec.Host)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("dialing host %s: %w", mvmMachine.Spec.Host, err)
+		}
+
+		if err := mvmClient.DeleteMicroVM(ctx, string(mvmMachine.UID)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("deleting microvm %s on host %s: %w", mvmMachine.UID, mvmMachine.Spec.Host, err)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(mvmMachine, MicrovmMachineFinalizer)
+
+	return ctrl.Result{}, nil
+}
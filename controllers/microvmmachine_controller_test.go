@@ -0,0 +1,227 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+)
+
+// fakeMvmClient is a hand-rolled controllers.MvmClient: flintsim speaks gRPC
+// request/response types rather than MvmClient's plain (ctx, id string)
+// signatures, so it can't be reused directly here.
+type fakeMvmClient struct {
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+func newFakeMvmClientFunc(fake *fakeMvmClient) controllers.MvmClientFunc {
+	return func(address string) (controllers.MvmClient, error) {
+		return fake, nil
+	}
+}
+
+func (f *fakeMvmClient) ListMicroVMs(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]string, 0, len(f.created))
+	for id := range f.created {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (f *fakeMvmClient) CreateMicroVM(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.created == nil {
+		f.created = map[string]bool{}
+	}
+
+	f.created[id] = true
+
+	return nil
+}
+
+func (f *fakeMvmClient) GetMicroVM(ctx context.Context, id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.created[id], nil
+}
+
+func (f *fakeMvmClient) DeleteMicroVM(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.created, id)
+
+	return nil
+}
+
+// erroringMvmClientFunc fails every dial attempt, for tests that shouldn't
+// need a host client at all.
+func erroringMvmClientFunc(address string) (controllers.MvmClient, error) {
+	return nil, fmt.Errorf("unexpected dial to %s", address)
+}
+
+func TestMachineReconciliationAssignsHostAndCreatesMicroVM(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
+
+	createReadyHostPool(ctx, g, "pool-"+ns.Name)
+
+	mvmMachine := createMicrovmMachine(ns.Name)
+	mvmMachine.Spec.PlacementPolicy = &infrav1.PlacementPolicy{PoolRef: "pool-" + ns.Name}
+
+	createObjects(ctx, g, createCluster(ns.Name), createMachine(ns.Name), mvmMachine)
+	// No kubeconfig secret is created for the tenant cluster, so the
+	// reconciler takes the "API server not ready" branch after assigning a
+	// host and reconciling the microvm - the same node-readiness path
+	// TestClusterReconciliationWithClusterEndpointAPIServerNotReady exercises
+	// for the cluster reconciler, and for the same reason: this harness's
+	// kubeconfig secrets don't carry credentials a real API server accepts,
+	// so there's no way to stand up a genuinely reachable tenant client here.
+
+	fake := &fakeMvmClient{}
+
+	result, err := reconcileMachine(ns.Name, newFakeMvmClientFunc(fake))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Requeue).To(BeFalse())
+	g.Expect(result.RequeueAfter).To(Equal(time.Duration(30 * time.Second)))
+
+	reconciled, err := getMicrovmMachine(ctx, ns.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Spec.Host).To(Equal("10.0.0.1:9090"))
+	g.Expect(reconciled.Status.Ready).To(BeFalse())
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	g.Expect(fake.created).To(HaveKey(string(reconciled.UID)))
+}
+
+func TestMachineReconciliationNoHostAssigned(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
+
+	createObjects(ctx, g, createCluster(ns.Name), createMachine(ns.Name), createMicrovmMachine(ns.Name))
+
+	result, err := reconcileMachine(ns.Name, erroringMvmClientFunc)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Duration(30 * time.Second)))
+
+	reconciled, err := getMicrovmMachine(ctx, ns.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Spec.Host).To(BeEmpty())
+}
+
+func TestMachineReconciliationDelete(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
+
+	mvmMachine := createMicrovmMachine(ns.Name)
+	mvmMachine.Spec.Host = "10.0.0.1:9090"
+	mvmMachine.Finalizers = []string{
+		controllers.MicrovmMachineFinalizer,
+	}
+
+	createObjects(ctx, g, createCluster(ns.Name), createMachine(ns.Name), mvmMachine)
+
+	fake := &fakeMvmClient{created: map[string]bool{string(mvmMachine.UID): true}}
+
+	g.Expect(testEnv.GetClient().Delete(ctx, mvmMachine)).To(Succeed())
+
+	result, err := reconcileMachine(ns.Name, newFakeMvmClientFunc(fake))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Requeue).To(BeFalse())
+	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+	fake.mu.Lock()
+	g.Expect(fake.created).NotTo(HaveKey(string(mvmMachine.UID)))
+	fake.mu.Unlock()
+
+	// The real API server removes the object once the last finalizer is
+	// cleared by the reconciler.
+	_, err = getMicrovmMachine(ctx, ns.Name)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestMachineReconciliationDeleteNoHostAssigned(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
+
+	mvmMachine := createMicrovmMachine(ns.Name)
+	mvmMachine.Finalizers = []string{
+		controllers.MicrovmMachineFinalizer,
+	}
+
+	createObjects(ctx, g, createCluster(ns.Name), createMachine(ns.Name), mvmMachine)
+	g.Expect(testEnv.GetClient().Delete(ctx, mvmMachine)).To(Succeed())
+
+	// No host was ever assigned, so reconcileDelete must not try to dial one.
+	result, err := reconcileMachine(ns.Name, erroringMvmClientFunc)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+	_, err = getMicrovmMachine(ctx, ns.Name)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestMachineReconciliationMicrovmAlreadyDeleted(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
+
+	result, err := reconcileMachine(ns.Name, erroringMvmClientFunc)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Requeue).To(BeFalse())
+	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+	_, err = getMicrovmMachine(ctx, ns.Name)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestMachineReconciliationNotOwner(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	ns := testNamespace(t, g)
+
+	mvmMachine := createMicrovmMachine(ns.Name)
+	mvmMachine.ObjectMeta.OwnerReferences = nil
+
+	createObjects(ctx, g, createCluster(ns.Name), createMachine(ns.Name), mvmMachine)
+
+	result, err := reconcileMachine(ns.Name, erroringMvmClientFunc)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Requeue).To(BeFalse())
+	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+	reconciled, err := getMicrovmMachine(ctx, ns.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Ready).To(BeFalse())
+	g.Expect(reconciled.Spec.Host).To(BeEmpty())
+}
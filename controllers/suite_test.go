@@ -0,0 +1,185 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package controllers_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/clustercache"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/envtest"
+)
+
+// testClusterName is the name shared by the Cluster and MicrovmCluster
+// fixtures created by createCluster/createMicrovmCluster.
+const testClusterName = "test-cluster"
+
+// testMachineName is the name shared by the Machine and MicrovmMachine
+// fixtures created by createMachine/createMicrovmMachine.
+const testMachineName = "test-machine"
+
+// testEnv is the shared envtest environment for this package's controller
+// suites. It is booted once in TestMain rather than per-test, mirroring the
+// pattern used by upstream cluster-api.
+var testEnv *envtest.Environment //nolint:gochecknoglobals // shared test fixture, mirrors upstream cluster-api suites.
+
+func TestMain(m *testing.M) {
+	testEnv = envtest.NewTestEnvironment()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := testEnv.Start(ctx); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	cancel()
+
+	if err := testEnv.Stop(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(code)
+}
+
+// testNamespace creates a fresh namespace for a single test and registers
+// its cleanup, so tests running against the shared envtest API server don't
+// see each other's objects.
+func testNamespace(t *testing.T, g *WithT) *corev1.Namespace {
+	t.Helper()
+
+	ns, err := testEnv.CreateNamespace(context.Background(), "capmvm-cluster")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	t.Cleanup(func() {
+		g.Expect(testEnv.DeleteNamespace(context.Background(), ns)).To(Succeed())
+	})
+
+	return ns
+}
+
+func createCluster(namespace string) *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testClusterName,
+			Namespace: namespace,
+		},
+	}
+}
+
+func createMicrovmCluster(namespace string) *infrav1.MicrovmCluster {
+	return &infrav1.MicrovmCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testClusterName,
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Cluster",
+					Name:       testClusterName,
+					UID:        "00000000-0000-0000-0000-000000000001",
+				},
+			},
+		},
+	}
+}
+
+// createObjects creates every object in objs against the real envtest API
+// server, replacing the fake client's object-tracker seeding.
+func createObjects(ctx context.Context, g *WithT, objs ...client.Object) {
+	for _, obj := range objs {
+		g.Expect(testEnv.GetClient().Create(ctx, obj)).To(Succeed())
+	}
+}
+
+func reconcileCluster(namespace string) (ctrl.Result, error) {
+	reconciler := &controllers.MicrovmClusterReconciler{
+		Client:   testEnv.GetClient(),
+		Scheme:   testEnv.GetScheme(),
+		Recorder: record.NewFakeRecorder(32),
+		ClusterCache: clustercache.NewClusterCache(testEnv.GetClient(), clustercache.Options{
+			Scheme: testEnv.GetScheme(),
+		}),
+	}
+
+	return reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: namespace},
+	})
+}
+
+func getMicrovmCluster(ctx context.Context, namespace string) (*infrav1.MicrovmCluster, error) {
+	mvmCluster := &infrav1.MicrovmCluster{}
+	err := testEnv.GetClient().Get(ctx, types.NamespacedName{Name: testClusterName, Namespace: namespace}, mvmCluster)
+
+	return mvmCluster, err
+}
+
+func createMachine(namespace string) *clusterv1.Machine {
+	return &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testMachineName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel: testClusterName,
+			},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: testClusterName,
+		},
+	}
+}
+
+func createMicrovmMachine(namespace string) *infrav1.MicrovmMachine {
+	return &infrav1.MicrovmMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testMachineName,
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Machine",
+					Name:       testMachineName,
+					UID:        "00000000-0000-0000-0000-000000000002",
+				},
+			},
+		},
+	}
+}
+
+func reconcileMachine(namespace string, mvmClientFunc controllers.MvmClientFunc) (ctrl.Result, error) {
+	reconciler := &controllers.MicrovmMachineReconciler{
+		Client:        testEnv.GetClient(),
+		Scheme:        testEnv.GetScheme(),
+		Recorder:      record.NewFakeRecorder(32),
+		MvmClientFunc: mvmClientFunc,
+		ClusterCache: clustercache.NewClusterCache(testEnv.GetClient(), clustercache.Options{
+			Scheme: testEnv.GetScheme(),
+		}),
+	}
+
+	return reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testMachineName, Namespace: namespace},
+	})
+}
+
+func getMicrovmMachine(ctx context.Context, namespace string) (*infrav1.MicrovmMachine, error) {
+	mvmMachine := &infrav1.MicrovmMachine{}
+	err := testEnv.GetClient().Get(ctx, types.NamespacedName{Name: testMachineName, Namespace: namespace}, mvmMachine)
+
+	return mvmMachine, err
+}
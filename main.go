@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	goruntime "runtime"
 	"time"
 
 	client "github.com/liquidmetal-dev/controller-pkg/client"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	cgrecord "k8s.io/client-go/tools/record"
 	"k8s.io/component-base/logs"
@@ -37,6 +39,7 @@ import (
 	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	expclusterv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	"sigs.k8s.io/cluster-api/feature"
 	"sigs.k8s.io/cluster-api/util/flags"
 	"sigs.k8s.io/cluster-api/util/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -47,6 +50,7 @@ import (
 	//+kubebuilder:scaffold:imports
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/clustercache"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/version"
 )
 
@@ -81,20 +85,42 @@ var (
 	leaderElectionRenewDeadline time.Duration
 	leaderElectionRetryPeriod   time.Duration
 
+	enableContentionProfiling bool
+	kubeAPIQPS                float32
+	kubeAPIBurst              int
+	featureGates              string
+
 	logOptions     = logs.NewOptions()
 	managerOptions = flags.ManagerOptions{}
 )
 
 const (
-	defaultLeaderElectionDur   = 15 * time.Second
-	defaultLeaderElectRenew    = 10 * time.Second
-	defaultLeaderElectionRetry = 2 * time.Second
-	defaultSyncPeriod          = 10 * time.Minute
-	defaultWebhookPort         = 9443
-	defaultEventBurstSize      = 100
+	defaultLeaderElectionDur        = 15 * time.Second
+	defaultLeaderElectRenew         = 10 * time.Second
+	defaultLeaderElectionRetry      = 2 * time.Second
+	defaultSyncPeriod               = 10 * time.Minute
+	defaultWebhookPort              = 9443
+	defaultEventBurstSize           = 100
+	clusterCacheHealthCheckInterval = 30 * time.Second
+	defaultKubeAPIQPS               = 20.0
+	defaultKubeAPIBurst             = 30
 )
 
+// initFlags wires up every flag the manager understands, split across
+// initManagerFlags, initReconcilerFlags and initTLSFlags so each option has a
+// single place it's defined.
 func initFlags(fs *pflag.FlagSet) {
+	initManagerFlags(fs)
+	initReconcilerFlags(fs)
+	initTLSFlags(fs)
+
+	logs.AddFlags(fs, logs.SkipLoggingConfigurationFlags())
+	v1.AddFlags(logOptions, fs)
+}
+
+// initManagerFlags covers leader election, namespace scoping, diagnostics and
+// everything else that shapes the controller-runtime Manager itself.
+func initManagerFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(
 		&enableLeaderElection,
 		"leader-elect",
@@ -147,6 +173,13 @@ func initFlags(fs *pflag.FlagSet) {
 		"Bind address to expose the pprof profiler (e.g. localhost:6060)",
 	)
 
+	fs.BoolVar(
+		&enableContentionProfiling,
+		"enable-contention-profiling",
+		false,
+		"Enable lock contention profiling, for use with the profiler-address / diagnostics-address pprof endpoint.",
+	)
+
 	fs.StringVar(
 		&watchFilterValue,
 		"watch-filter",
@@ -158,6 +191,36 @@ func initFlags(fs *pflag.FlagSet) {
 		),
 	)
 
+	fs.StringVar(&healthAddr,
+		"health-addr",
+		":9440",
+		"The address the health endpoint binds to.",
+	)
+
+	fs.Float32Var(&kubeAPIQPS,
+		"kube-api-qps",
+		defaultKubeAPIQPS,
+		"Maximum queries per second allowed against the Kubernetes API server.",
+	)
+
+	fs.IntVar(&kubeAPIBurst,
+		"kube-api-burst",
+		defaultKubeAPIBurst,
+		"Maximum burst of queries allowed against the Kubernetes API server.",
+	)
+
+	fs.StringVar(&featureGates,
+		"feature-gates",
+		"",
+		"A set of key=value pairs that describe alpha/experimental feature gates, e.g. \"FeatureA=true,FeatureB=false\".",
+	)
+
+	flags.AddManagerOptions(fs, &managerOptions)
+}
+
+// initReconcilerFlags covers per-controller knobs: concurrency and how often
+// watched resources are resynced.
+func initReconcilerFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&microvmClusterConcurrency,
 		"microvmcluster-concurrency",
 		1,
@@ -175,7 +238,14 @@ func initFlags(fs *pflag.FlagSet) {
 		defaultSyncPeriod,
 		"The minimum interval at which watched resources are reconciled (e.g. 15m)",
 	)
+}
 
+// initTLSFlags covers the webhook server's listening port and certificates.
+// TLS minimum version/cipher suites and the diagnostics endpoint are flags on
+// managerOptions (registered by flags.AddManagerOptions in initManagerFlags);
+// GetManagerOptions turns them into the webhook.Options.TLSOpts applied in
+// setupManager.
+func initTLSFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&webhookPort,
 		"webhook-port",
 		defaultWebhookPort,
@@ -187,17 +257,6 @@ func initFlags(fs *pflag.FlagSet) {
 		"/tmp/k8s-webhook-server/serving-certs",
 		"Webhook Server Certificate Directory, is the directory that contains the server key and certificate",
 	)
-
-	fs.StringVar(&healthAddr,
-		"health-addr",
-		":9440",
-		"The address the health endpoint binds to.",
-	)
-
-	logs.AddFlags(fs, logs.SkipLoggingConfigurationFlags())
-	v1.AddFlags(logOptions, fs)
-
-	flags.AddManagerOptions(fs, &managerOptions)
 }
 
 func main() {
@@ -213,17 +272,13 @@ func main() {
 	}
 	ctrl.SetLogger(klog.Background())
 
-	_, metricsOptions, err := flags.GetManagerOptions(managerOptions)
-	if err != nil {
-		setupLog.Error(err, "Unable to start manager: invalid flags")
+	if err := feature.MutableGates.Set(featureGates); err != nil {
+		setupLog.Error(err, "unable to parse feature-gates")
+		os.Exit(1)
 	}
 
-	var watchNamespaces map[string]cache.Config
-	if watchNamespace != "" {
-		setupLog.Info("Watching cluster-api objects only in namespace for reconciliation", "namespace", watchNamespace)
-		watchNamespaces = map[string]cache.Config{
-			watchNamespace: {},
-		}
+	if enableContentionProfiling {
+		goruntime.SetBlockProfileRate(1)
 	}
 
 	if profilerAddress != "" {
@@ -240,35 +295,12 @@ func main() {
 		}()
 	}
 
-	// Machine and cluster operations can create enough events to trigger the event recorder spam filter
-	// Setting the burst size higher ensures all events will be recorded and submitted to the API
-	broadcaster := cgrecord.NewBroadcasterWithCorrelatorOptions(cgrecord.CorrelatorOptions{
-		BurstSize: defaultEventBurstSize,
-	})
-
 	restConfig := ctrl.GetConfigOrDie()
 	restConfig.UserAgent = "cluster-api-provider-microvm-controller"
+	restConfig.QPS = kubeAPIQPS
+	restConfig.Burst = kubeAPIBurst
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                     scheme,
-		Metrics:                    *metricsOptions,
-		LeaderElection:             enableLeaderElection,
-		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
-		LeaderElectionID:           "controller-leader-elect-capmvm",
-		LeaderElectionNamespace:    leaderElectionNamespace,
-		RenewDeadline:              &leaderElectionRenewDeadline,
-		RetryPeriod:                &leaderElectionRetryPeriod,
-		Cache: cache.Options{
-			DefaultNamespaces: watchNamespaces,
-			SyncPeriod:        &syncPeriod,
-		},
-		WebhookServer: webhook.NewServer(webhook.Options{
-			Port:    webhookPort,
-			CertDir: webhookCertDir,
-		}),
-		EventBroadcaster:       broadcaster,
-		HealthProbeBindAddress: healthAddr,
-	})
+	mgr, err := setupManager(restConfig)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
@@ -305,17 +337,75 @@ func main() {
 	}
 }
 
+// setupManager builds the controller-runtime Manager: leader election,
+// caching/namespace scoping, the webhook server (with the TLS options
+// derived from managerOptions), and the diagnostics-guarded metrics
+// endpoint. It is the single place ctrl.NewManager gets called.
+func setupManager(restConfig *rest.Config) (ctrl.Manager, error) {
+	tlsOptions, metricsOptions, err := flags.GetManagerOptions(managerOptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manager flags: %w", err)
+	}
+
+	var watchNamespaces map[string]cache.Config
+	if watchNamespace != "" {
+		setupLog.Info("Watching cluster-api objects only in namespace for reconciliation", "namespace", watchNamespace)
+		watchNamespaces = map[string]cache.Config{
+			watchNamespace: {},
+		}
+	}
+
+	// Machine and cluster operations can create enough events to trigger the event recorder spam filter
+	// Setting the burst size higher ensures all events will be recorded and submitted to the API
+	broadcaster := cgrecord.NewBroadcasterWithCorrelatorOptions(cgrecord.CorrelatorOptions{
+		BurstSize: defaultEventBurstSize,
+	})
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                     scheme,
+		Metrics:                    *metricsOptions,
+		LeaderElection:             enableLeaderElection,
+		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
+		LeaderElectionID:           "controller-leader-elect-capmvm",
+		LeaderElectionNamespace:    leaderElectionNamespace,
+		RenewDeadline:              &leaderElectionRenewDeadline,
+		RetryPeriod:                &leaderElectionRetryPeriod,
+		Cache: cache.Options{
+			DefaultNamespaces: watchNamespaces,
+			SyncPeriod:        &syncPeriod,
+		},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+			TLSOpts: tlsOptions,
+		}),
+		EventBroadcaster:       broadcaster,
+		HealthProbeBindAddress: healthAddr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating manager: %w", err)
+	}
+
+	return mgr, nil
+}
+
 func setupReconcilers(ctx context.Context, mgr ctrl.Manager) error {
 	managerOptions := controller.Options{
 		MaxConcurrentReconciles: microvmClusterConcurrency,
 		RecoverPanic:            ptr.To[bool](true),
 	}
 
+	clusterCache := clustercache.NewClusterCache(mgr.GetClient(), clustercache.Options{
+		Scheme:              mgr.GetScheme(),
+		HealthCheckInterval: clusterCacheHealthCheckInterval,
+	})
+
 	if err := (&controllers.MicrovmClusterReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
 		Recorder:         mgr.GetEventRecorderFor("microvmcluster-controller"),
 		WatchFilterValue: watchFilterValue,
+		ClusterCache:     clusterCache,
 	}).SetupWithManager(ctx, mgr, managerOptions); err != nil {
 		return fmt.Errorf("unable to create microvm cluster controller: %w", err)
 	}
@@ -326,10 +416,20 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) error {
 		Recorder:         mgr.GetEventRecorderFor("microvmmachine-controller"),
 		WatchFilterValue: watchFilterValue,
 		MvmClientFunc:    client.NewFlintlockClient,
+		ClusterCache:     clusterCache,
 	}).SetupWithManager(ctx, mgr, managerOptions); err != nil {
 		return fmt.Errorf("unable to create microvm machine controller: %w", err)
 	}
 
+	if err := (&controllers.MicrovmHostPoolReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Recorder:      mgr.GetEventRecorderFor("microvmhostpool-controller"),
+		MvmClientFunc: client.NewFlintlockClient,
+	}).SetupWithManager(ctx, mgr, managerOptions); err != nil {
+		return fmt.Errorf("unable to create microvm host pool controller: %w", err)
+	}
+
 	return nil
 }
 
@@ -346,6 +446,10 @@ func setupWebhooks(mgr ctrl.Manager) error {
 		return fmt.Errorf("unable to setup MicrovmMachineTemplate webhook:%w", err)
 	}
 
+	if err := (&infrav1.MicrovmHostPool{}).SetupWebhookWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to setup MicrovmHostPool webhook:%w", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,61 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package flintsim
+
+import (
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+
+	microvmv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+)
+
+// Simulator owns a Server and the gRPC listener serving it, so callers get a
+// dial-able address from Addr and a single Stop to tear everything
+// down.
+type Simulator struct {
+	*Server
+
+	grpcServer *grpc.Server
+	listener   net.Listener
+}
+
+// New returns a Simulator bound to an ephemeral localhost port. Call Start to
+// begin serving.
+func New() (*Simulator, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("flintsim: listening: %w", err)
+	}
+
+	sim := &Simulator{
+		Server:   NewServer(),
+		listener: lis,
+	}
+
+	sim.grpcServer = grpc.NewServer()
+	microvmv1.RegisterMicroVMServer(sim.grpcServer, sim.Server)
+
+	return sim, nil
+}
+
+// Addr returns the address the simulator is listening on, suitable for
+// passing to controller-pkg/client.NewFlintlockClient.
+func (s *Simulator) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Start begins serving in the background. It returns immediately; call Stop
+// to shut the server down.
+func (s *Simulator) Start() {
+	go func() {
+		_ = s.grpcServer.Serve(s.listener)
+	}()
+}
+
+// Stop gracefully shuts the simulator down.
+func (s *Simulator) Stop() {
+	s.grpcServer.GracefulStop()
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Package flintsim is an in-process fake of the flintlock MicroVM gRPC
+// service, in the same spirit as vSphere's govmomi-based vcsim: it lets
+// controller tests exercise the real controller-pkg/client.NewFlintlockClient
+// factory end-to-end, including create/get/delete/list flows, without a
+// real flintlock host or hypervisor.
+package flintsim
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	microvmv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+)
+
+// Faults configures the failure modes a Server injects, so tests can exercise
+// the controller's retry/backoff paths without a real flaky host.
+type Faults struct {
+	// Latency is added to every request before it is otherwise handled.
+	Latency time.Duration
+	// CreateErrorRate is the fraction (0..1) of CreateMicroVM calls that fail
+	// with a transient Unavailable error.
+	CreateErrorRate float64
+	// DeleteNotFound makes DeleteMicroVM return NotFound instead of
+	// succeeding, simulating a microvm that's already gone from the host.
+	DeleteNotFound bool
+	// HostAtCapacity makes CreateMicroVM return ResourceExhausted, simulating
+	// a host with no room left for new microvms.
+	HostAtCapacity bool
+}
+
+// Server is a fake implementation of the flintlock MicroVM gRPC service. It
+// keeps created microvms in memory and reports them back through
+// Get/List/Delete exactly as a real flintlock host would, modulo Faults.
+type Server struct {
+	microvmv1.UnimplementedMicroVMServer
+
+	mu     sync.Mutex
+	vms    map[string]*flintlocktypes.MicroVM
+	calls  int
+	faults Faults
+}
+
+// NewServer returns a Server with no microvms and no injected faults. Use
+// SetFaults to adjust fault injection between test phases.
+func NewServer() *Server {
+	return &Server{
+		vms: map[string]*flintlocktypes.MicroVM{},
+	}
+}
+
+// SetFaults replaces the active fault-injection configuration.
+func (s *Server) SetFaults(f Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = f
+}
+
+func (s *Server) delay(ctx context.Context) error {
+	s.mu.Lock()
+	latency := s.faults.Latency
+	s.mu.Unlock()
+
+	if latency <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CreateMicroVM implements microvmv1.MicroVMServer.
+func (s *Server) CreateMicroVM(ctx context.Context, req *microvmv1.CreateMicroVMRequest) (*microvmv1.CreateMicroVMResponse, error) {
+	if err := s.delay(ctx); err != nil {
+		return nil, fmt.Errorf("flintsim: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.faults.HostAtCapacity {
+		return nil, status.Error(codes.ResourceExhausted, "flintsim: host has no capacity for new microvms")
+	}
+
+	if s.faults.CreateErrorRate > 0 && shouldFail(s.faults.CreateErrorRate, s.calls) {
+		s.calls++
+
+		return nil, status.Error(codes.Unavailable, "flintsim: transient error injected by CreateErrorRate")
+	}
+
+	s.calls++
+
+	vm := req.GetMicrovm()
+	if vm.GetSpec() == nil {
+		vm.Spec = &flintlocktypes.MicroVMSpec{}
+	}
+
+	vm.Status = &flintlocktypes.MicroVMStatus{
+		State: flintlocktypes.MicroVMStatus_CREATED,
+	}
+
+	s.vms[vmKey(vm.GetId(), vm.GetNamespace())] = vm
+
+	return &microvmv1.CreateMicroVMResponse{Microvm: vm}, nil
+}
+
+// GetMicroVM implements microvmv1.MicroVMServer.
+func (s *Server) GetMicroVM(ctx context.Context, req *microvmv1.GetMicroVMRequest) (*microvmv1.GetMicroVMResponse, error) {
+	if err := s.delay(ctx); err != nil {
+		return nil, fmt.Errorf("flintsim: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vm, ok := s.vms[vmKey(req.GetUid(), req.GetNamespace())]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "flintsim: microvm not found")
+	}
+
+	return &microvmv1.GetMicroVMResponse{Microvm: vm}, nil
+}
+
+// DeleteMicroVM implements microvmv1.MicroVMServer.
+func (s *Server) DeleteMicroVM(ctx context.Context, req *microvmv1.DeleteMicroVMRequest) (*microvmv1.DeleteMicroVMResponse, error) {
+	if err := s.delay(ctx); err != nil {
+		return nil, fmt.Errorf("flintsim: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.faults.DeleteNotFound {
+		return nil, status.Error(codes.NotFound, "flintsim: microvm not found (DeleteNotFound fault)")
+	}
+
+	delete(s.vms, vmKey(req.GetUid(), req.GetNamespace()))
+
+	return &microvmv1.DeleteMicroVMResponse{}, nil
+}
+
+// ListMicroVMs implements microvmv1.MicroVMServer.
+func (s *Server) ListMicroVMs(ctx context.Context, req *microvmv1.ListMicroVMsRequest) (*microvmv1.ListMicroVMsResponse, error) {
+	if err := s.delay(ctx); err != nil {
+		return nil, fmt.Errorf("flintsim: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vms := make([]*flintlocktypes.MicroVM, 0, len(s.vms))
+
+	for _, vm := range s.vms {
+		if req.GetNamespace() != "" && vm.GetNamespace() != req.GetNamespace() {
+			continue
+		}
+
+		vms = append(vms, vm)
+	}
+
+	return &microvmv1.ListMicroVMsResponse{Microvm: vms}, nil
+}
+
+func vmKey(uid, namespace string) string {
+	return namespace + "/" + uid
+}
+
+// shouldFail deterministically fails every 1/rate-th call. A deterministic
+// sequence keeps tests that assert "N calls, M failures" reproducible,
+// rather than flaking on math/rand.
+func shouldFail(rate float64, call int) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	every := int(1 / rate)
+	if every <= 0 {
+		every = 1
+	}
+
+	return call%every == 0
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0.
+
+package flintsim_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	microvmv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/test/framework/flintsim"
+)
+
+func TestCreateGetDeleteRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	srv := flintsim.NewServer()
+
+	created, err := srv.CreateMicroVM(ctx, createMicroVMRequest("vm-1", "default"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(created.GetMicrovm().GetId()).To(Equal("vm-1"))
+
+	got, err := srv.GetMicroVM(ctx, &microvmv1.GetMicroVMRequest{Uid: "vm-1", Namespace: "default"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got.GetMicrovm().GetId()).To(Equal("vm-1"))
+
+	listed, err := srv.ListMicroVMs(ctx, &microvmv1.ListMicroVMsRequest{Namespace: "default"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(listed.GetMicrovm()).To(HaveLen(1))
+
+	_, err = srv.DeleteMicroVM(ctx, &microvmv1.DeleteMicroVMRequest{Uid: "vm-1", Namespace: "default"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = srv.GetMicroVM(ctx, &microvmv1.GetMicroVMRequest{Uid: "vm-1", Namespace: "default"})
+	g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+}
+
+func TestFaultsHostAtCapacity(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	srv := flintsim.NewServer()
+	srv.SetFaults(flintsim.Faults{HostAtCapacity: true})
+
+	_, err := srv.CreateMicroVM(ctx, createMicroVMRequest("vm-1", "default"))
+	g.Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+}
+
+func TestFaultsDeleteNotFound(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	srv := flintsim.NewServer()
+	srv.SetFaults(flintsim.Faults{DeleteNotFound: true})
+
+	_, err := srv.DeleteMicroVM(ctx, &microvmv1.DeleteMicroVMRequest{Uid: "vm-1", Namespace: "default"})
+	g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+}
+
+func createMicroVMRequest(id, namespace string) *microvmv1.CreateMicroVMRequest {
+	return &microvmv1.CreateMicroVMRequest{
+		Microvm: &flintlocktypes.MicroVM{
+			Id:        id,
+			Namespace: namespace,
+			Spec:      &flintlocktypes.MicroVMSpec{},
+		},
+	}
+}